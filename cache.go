@@ -0,0 +1,156 @@
+package main
+
+import (
+	"container/list"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const defaultLRUCapacity = 256
+
+// TileCache stores fetched tiles keyed by (panoId, zoom, x, y) so repeat runs
+// and retries don't re-hit Google's tile server for tiles we already have.
+type TileCache interface {
+	Get(panoId string, zoom, x, y int) ([]byte, bool)
+	Put(panoId string, zoom, x, y int, data []byte) error
+}
+
+// FSTileCache stores tiles on disk under <Dir>/<panoId>/<zoom>/<x>_<y>.jpg.
+// A zero TTL means tiles never expire, which is the right default since a
+// given panoId's tiles are immutable.
+type FSTileCache struct {
+	Dir string
+	TTL time.Duration
+}
+
+func NewFSTileCache(dir string, ttl time.Duration) *FSTileCache {
+	return &FSTileCache{Dir: dir, TTL: ttl}
+}
+
+func (c *FSTileCache) path(panoId string, zoom, x, y int) string {
+	return filepath.Join(c.Dir, panoId, strconv.Itoa(zoom), fmt.Sprintf("%d_%d.jpg", x, y))
+}
+
+func (c *FSTileCache) Get(panoId string, zoom, x, y int) ([]byte, bool) {
+	p := c.path(panoId, zoom, x, y)
+
+	info, err := os.Stat(p)
+	if err != nil {
+		return nil, false
+	}
+
+	if c.TTL > 0 && time.Since(info.ModTime()) > c.TTL {
+		return nil, false
+	}
+
+	data, err := os.ReadFile(p)
+	if err != nil {
+		return nil, false
+	}
+
+	return data, true
+}
+
+func (c *FSTileCache) Put(panoId string, zoom, x, y int, data []byte) error {
+	p := c.path(panoId, zoom, x, y)
+
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		return err
+	}
+
+	return os.WriteFile(p, data, 0644)
+}
+
+// lruTileCache keeps the most recently used tiles in memory in front of a
+// slower backing cache, so repeated requests for the same hot panoIds don't
+// round-trip to disk.
+type lruTileCache struct {
+	capacity int
+	next     TileCache
+
+	mu    sync.Mutex
+	ll    *list.List
+	items map[string]*list.Element
+}
+
+type lruEntry struct {
+	key  string
+	data []byte
+}
+
+func NewLRUTileCache(capacity int, next TileCache) *lruTileCache {
+	if capacity <= 0 {
+		capacity = defaultLRUCapacity
+	}
+
+	return &lruTileCache{
+		capacity: capacity,
+		next:     next,
+		ll:       list.New(),
+		items:    make(map[string]*list.Element),
+	}
+}
+
+func lruKey(panoId string, zoom, x, y int) string {
+	return fmt.Sprintf("%s/%d/%d_%d", panoId, zoom, x, y)
+}
+
+func (c *lruTileCache) Get(panoId string, zoom, x, y int) ([]byte, bool) {
+	key := lruKey(panoId, zoom, x, y)
+
+	c.mu.Lock()
+	if el, ok := c.items[key]; ok {
+		c.ll.MoveToFront(el)
+		data := el.Value.(*lruEntry).data
+		c.mu.Unlock()
+		return data, true
+	}
+	c.mu.Unlock()
+
+	if c.next == nil {
+		return nil, false
+	}
+
+	data, ok := c.next.Get(panoId, zoom, x, y)
+	if ok {
+		c.promote(key, data)
+	}
+
+	return data, ok
+}
+
+func (c *lruTileCache) Put(panoId string, zoom, x, y int, data []byte) error {
+	c.promote(lruKey(panoId, zoom, x, y), data)
+
+	if c.next == nil {
+		return nil
+	}
+
+	return c.next.Put(panoId, zoom, x, y, data)
+}
+
+func (c *lruTileCache) promote(key string, data []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.items[key]; ok {
+		el.Value.(*lruEntry).data = data
+		c.ll.MoveToFront(el)
+		return
+	}
+
+	el := c.ll.PushFront(&lruEntry{key: key, data: data})
+	c.items[key] = el
+
+	if c.ll.Len() > c.capacity {
+		oldest := c.ll.Back()
+		if oldest != nil {
+			c.ll.Remove(oldest)
+			delete(c.items, oldest.Value.(*lruEntry).key)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestFSTileCache_PutGet(t *testing.T) {
+	cache := NewFSTileCache(t.TempDir(), 0)
+
+	if _, ok := cache.Get("pano1", 4, 1, 2); ok {
+		t.Fatal("expected miss before Put")
+	}
+
+	want := []byte("tile-bytes")
+	if err := cache.Put("pano1", 4, 1, 2, want); err != nil {
+		t.Fatalf("Put returned error: %v", err)
+	}
+
+	got, ok := cache.Get("pano1", 4, 1, 2)
+	if !ok {
+		t.Fatal("expected hit after Put")
+	}
+	if string(got) != string(want) {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
+
+func TestFSTileCache_LayoutByPanoZoomXY(t *testing.T) {
+	dir := t.TempDir()
+	cache := NewFSTileCache(dir, 0)
+
+	if err := cache.Put("pano1", 4, 1, 2, []byte("data")); err != nil {
+		t.Fatalf("Put returned error: %v", err)
+	}
+
+	want := filepath.Join(dir, "pano1", "4", "1_2.jpg")
+	if got := cache.path("pano1", 4, 1, 2); got != want {
+		t.Fatalf("got path %q, want %q", got, want)
+	}
+}
+
+func TestLRUTileCache_HitsWithoutBackingStore(t *testing.T) {
+	cache := NewLRUTileCache(1, nil)
+
+	if err := cache.Put("pano1", 4, 0, 0, []byte("a")); err != nil {
+		t.Fatalf("Put returned error: %v", err)
+	}
+
+	if _, ok := cache.Get("pano1", 4, 0, 0); !ok {
+		t.Fatal("expected in-memory hit")
+	}
+}
+
+func TestLRUTileCache_EvictsOldestBeyondCapacity(t *testing.T) {
+	cache := NewLRUTileCache(1, nil)
+
+	cache.Put("pano1", 4, 0, 0, []byte("a"))
+	cache.Put("pano1", 4, 0, 1, []byte("b"))
+
+	if _, ok := cache.Get("pano1", 4, 0, 0); ok {
+		t.Fatal("expected the first tile to be evicted once capacity is exceeded")
+	}
+	if _, ok := cache.Get("pano1", 4, 0, 1); !ok {
+		t.Fatal("expected the most recently added tile to still be cached")
+	}
+}
+
+func TestLRUTileCache_FallsThroughToBackingStore(t *testing.T) {
+	backing := NewFSTileCache(t.TempDir(), 0)
+	cache := NewLRUTileCache(defaultLRUCapacity, backing)
+
+	if err := backing.Put("pano1", 4, 3, 3, []byte("from-disk")); err != nil {
+		t.Fatalf("Put returned error: %v", err)
+	}
+
+	got, ok := cache.Get("pano1", 4, 3, 3)
+	if !ok {
+		t.Fatal("expected LRU cache to fall through to the backing store")
+	}
+	if string(got) != "from-disk" {
+		t.Fatalf("got %q, want %q", got, "from-disk")
+	}
+}
@@ -0,0 +1,27 @@
+package main
+
+import "errors"
+
+// ErrTileNotFound is a terminal error: the tile server has nothing at this
+// (panoId, zoom, x, y), so retrying will not help.
+var ErrTileNotFound = errors.New("tile not found")
+
+// retryableTileError marks a fetchTile failure as transient (a failed HTTP
+// request, HTTP 429/5xx, or a body read error), so fetchTileWithRetry knows
+// it's worth retrying.
+type retryableTileError struct {
+	err error
+}
+
+func (e *retryableTileError) Error() string {
+	return e.err.Error()
+}
+
+func (e *retryableTileError) Unwrap() error {
+	return e.err
+}
+
+func isRetryable(err error) bool {
+	var re *retryableTileError
+	return errors.As(err, &re)
+}
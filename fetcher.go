@@ -0,0 +1,364 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/davidbyttow/govips/v2/vips"
+	"golang.org/x/time/rate"
+)
+
+const tileSize = 512
+const defaultZoom = 4
+const trimThreshold = 6
+
+const defaultConcurrency = 8
+const defaultMaxRetries = 3
+const defaultBaseBackoff = 200 * time.Millisecond
+
+type dimensions struct {
+	width, height int
+}
+
+type tileConfig struct {
+	panoId string
+	zoom   int
+	x, y   int
+	url    string
+}
+
+type tileData struct {
+	x, y int
+	data []byte
+}
+
+type RLHTTPClient struct {
+	client *http.Client
+	rl     *rate.Limiter
+}
+
+func (c *RLHTTPClient) Do(req *http.Request) (*http.Response, error) {
+	ctx := context.Background()
+	err := c.rl.Wait(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+func NewClient(rl *rate.Limiter) *RLHTTPClient {
+	c := &RLHTTPClient{
+		client: http.DefaultClient,
+		rl:     rl,
+	}
+
+	return c
+}
+
+func makePanoUrl(panoId string, zoom, x, y int) string {
+	return fmt.Sprintf(
+		"https://cbk0.google.com/cbk?output=tile&panoid=%s&zoom=%d&x=%d&y=%d",
+		panoId, zoom, x, y,
+	)
+}
+
+func fetchTile(config tileConfig, rlClient *RLHTTPClient, cache TileCache) ([]byte, error) {
+	if cache != nil {
+		if data, ok := cache.Get(config.panoId, config.zoom, config.x, config.y); ok {
+			return data, nil
+		}
+	}
+
+	req, err := http.NewRequest("GET", config.url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := rlClient.Do(req)
+	if err != nil {
+		return nil, &retryableTileError{err}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("tile %d,%d: %w", config.x, config.y, ErrTileNotFound)
+	}
+
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= http.StatusInternalServerError {
+		return nil, &retryableTileError{fmt.Errorf("tile server returned %s", resp.Status)}
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, &retryableTileError{err}
+	}
+
+	if cache != nil {
+		if err := cache.Put(config.panoId, config.zoom, config.x, config.y, data); err != nil {
+			log.Printf("[Cache] failed to store tile %d,%d: %v", config.x, config.y, err)
+		}
+	}
+
+	return data, nil
+}
+
+func getDimensionsFromZoom(zoom int) dimensions {
+	return dimensions{
+		width:  1 << zoom,
+		height: 1 << (zoom - 1),
+	}
+}
+
+func getTilesConfig(panoId string, zoom int, dim dimensions) []tileConfig {
+	tiles := make([]tileConfig, 0, dim.width*dim.height)
+
+	for x := 0; x < dim.width; x++ {
+		for y := 0; y < dim.height; y++ {
+			tiles = append(tiles, tileConfig{
+				panoId: panoId,
+				zoom:   zoom,
+				x:      x,
+				y:      y,
+				url:    makePanoUrl(panoId, zoom, x, y),
+			})
+		}
+	}
+
+	return tiles
+}
+
+func compositePano(tiles []tileData, dim dimensions) (*vips.ImageRef, error) {
+	pano, err := vips.Black(tileSize*dim.width, tileSize*dim.height)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, tile := range tiles {
+		image, err := vips.NewImageFromBuffer(tile.data)
+		if err != nil {
+			return nil, err
+		}
+
+		err = pano.Insert(image, tileSize*tile.x, tileSize*tile.y, false, &vips.ColorRGBA{})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	left, top, width, height, err := pano.FindTrim(trimThreshold, &vips.Color{})
+	if err != nil {
+		return nil, err
+	}
+
+	err = pano.ExtractArea(left, top, width, height)
+	if err != nil {
+		return nil, err
+	}
+
+	return pano, nil
+}
+
+// FetchResult holds the tiles a TileFetcher managed to fetch, plus the ones
+// that failed permanently, either with a terminal error or after exhausting
+// retries (only populated when AllowPartial is set).
+type FetchResult struct {
+	Tiles       []tileData
+	FailedTiles []tileConfig
+}
+
+// TileFetcher fetches the tiles that make up a panorama, pipelining the
+// HTTP round-trips across a small worker pool while the underlying
+// RLHTTPClient still enforces the global request rate.
+type TileFetcher struct {
+	RLClient    *RLHTTPClient
+	Concurrency int
+	Cache       TileCache
+
+	// MaxRetries is the number of extra attempts made for a tile after a
+	// retryable error (HTTP 429/5xx or a read error). BaseBackoff is the
+	// starting delay, doubled on each attempt and jittered.
+	MaxRetries  int
+	BaseBackoff time.Duration
+
+	// AllowPartial lets getTiles return a partial FetchResult instead of an
+	// error when some tiles fail permanently, so compositePano can still
+	// produce a panorama with the failed regions left black.
+	AllowPartial bool
+}
+
+func NewTileFetcher(rlClient *RLHTTPClient, concurrency int, cache TileCache) *TileFetcher {
+	if concurrency <= 0 {
+		concurrency = defaultConcurrency
+	}
+
+	return &TileFetcher{
+		RLClient:     rlClient,
+		Concurrency:  concurrency,
+		Cache:        cache,
+		MaxRetries:   defaultMaxRetries,
+		BaseBackoff:  defaultBaseBackoff,
+		AllowPartial: true,
+	}
+}
+
+type fetchOutcome struct {
+	tile   tileData
+	failed *tileConfig
+}
+
+func (tf *TileFetcher) worker(ctx context.Context, jobs <-chan tileConfig, outcomes chan<- fetchOutcome, errs chan<- error, cancel context.CancelFunc) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case job, ok := <-jobs:
+			if !ok {
+				return
+			}
+
+			data, err := tf.fetchTileWithRetry(job)
+			if err != nil {
+				if tf.AllowPartial {
+					select {
+					case outcomes <- fetchOutcome{failed: &job}:
+					case <-ctx.Done():
+					}
+					continue
+				}
+
+				select {
+				case errs <- fmt.Errorf("fetch tile %d,%d: %w", job.x, job.y, err):
+					cancel()
+				default:
+				}
+				return
+			}
+
+			select {
+			case outcomes <- fetchOutcome{tile: tileData{x: job.x, y: job.y, data: data}}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+}
+
+// fetchTileWithRetry retries config.url up to tf.MaxRetries times on
+// retryable errors, backing off exponentially with jitter between attempts.
+// A terminal error (e.g. 404) is returned immediately without retrying.
+func (tf *TileFetcher) fetchTileWithRetry(config tileConfig) ([]byte, error) {
+	var lastErr error
+
+	for attempt := 0; attempt <= tf.MaxRetries; attempt++ {
+		data, err := fetchTile(config, tf.RLClient, tf.Cache)
+		if err == nil {
+			return data, nil
+		}
+
+		lastErr = err
+		if !isRetryable(err) {
+			return nil, err
+		}
+
+		if attempt == tf.MaxRetries {
+			break
+		}
+
+		delay := backoffWithJitter(tf.BaseBackoff, attempt)
+		log.Printf("[Retry] tile %d,%d attempt %d/%d failed: %v, retrying in %s", config.x, config.y, attempt+1, tf.MaxRetries+1, err, delay)
+		time.Sleep(delay)
+	}
+
+	return nil, lastErr
+}
+
+func backoffWithJitter(base time.Duration, attempt int) time.Duration {
+	if base <= 0 {
+		return 0
+	}
+
+	backoff := base * time.Duration(int64(1)<<uint(attempt))
+	return backoff/2 + time.Duration(rand.Int63n(int64(backoff)/2+1))
+}
+
+// getTiles fetches every tile of the pano's (zoom, dim) tile grid, pipelining
+// I/O across tf.Concurrency workers.
+func (tf *TileFetcher) getTiles(panoId string, zoom int, dim dimensions) (*FetchResult, error) {
+	return tf.getTilesFromConfig(getTilesConfig(panoId, zoom, dim))
+}
+
+// getTilesFromConfig fetches the tiles described by tiles, pipelining I/O
+// across tf.Concurrency workers. It is split out from getTiles so tests can
+// fetch against arbitrary URLs (e.g. an httptest.Server). Unless
+// tf.AllowPartial is set, the first permanently-failing tile cancels the
+// remaining work and is returned as an error.
+func (tf *TileFetcher) getTilesFromConfig(tiles []tileConfig) (*FetchResult, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	jobs := make(chan tileConfig)
+	outcomes := make(chan fetchOutcome, len(tiles))
+	errs := make(chan error, 1)
+
+	var wg sync.WaitGroup
+	for i := 0; i < tf.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			tf.worker(ctx, jobs, outcomes, errs, cancel)
+		}()
+	}
+
+	go func() {
+		defer close(jobs)
+		for _, tile := range tiles {
+			select {
+			case jobs <- tile:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	go func() {
+		wg.Wait()
+		close(outcomes)
+	}()
+
+	result := &FetchResult{
+		Tiles:       make([]tileData, 0, len(tiles)),
+		FailedTiles: make([]tileConfig, 0),
+	}
+
+	for outcome := range outcomes {
+		if outcome.failed != nil {
+			result.FailedTiles = append(result.FailedTiles, *outcome.failed)
+			continue
+		}
+		result.Tiles = append(result.Tiles, outcome.tile)
+	}
+
+	select {
+	case err := <-errs:
+		return nil, err
+	default:
+	}
+
+	if !tf.AllowPartial && len(result.Tiles) != len(tiles) {
+		return nil, fmt.Errorf("expected %d tiles, got %d", len(tiles), len(result.Tiles))
+	}
+
+	return result, nil
+}
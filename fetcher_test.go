@@ -0,0 +1,162 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+func TestTileFetcher_RetriesTransientErrorsThenSucceeds(t *testing.T) {
+	var attempts int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&attempts, 1) <= 2 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("tile-data"))
+	}))
+	defer server.Close()
+
+	rl := rate.NewLimiter(rate.Inf, 1)
+	fetcher := NewTileFetcher(NewClient(rl), 1, nil)
+	fetcher.BaseBackoff = time.Millisecond
+
+	config := tileConfig{panoId: "pano", zoom: 1, x: 0, y: 0, url: server.URL}
+
+	data, err := fetcher.fetchTileWithRetry(config)
+	if err != nil {
+		t.Fatalf("expected eventual success, got error: %v", err)
+	}
+	if string(data) != "tile-data" {
+		t.Fatalf("got %q, want %q", data, "tile-data")
+	}
+	if attempts != 3 {
+		t.Fatalf("expected 3 attempts, got %d", attempts)
+	}
+}
+
+func TestTileFetcher_AllowPartial_CollectsFailedTiles(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("x") == "0" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("tile-data"))
+	}))
+	defer server.Close()
+
+	rl := rate.NewLimiter(rate.Inf, 1)
+	fetcher := NewTileFetcher(NewClient(rl), 4, nil)
+	fetcher.AllowPartial = true
+
+	tiles := []tileConfig{
+		{panoId: "pano", zoom: 1, x: 0, y: 0, url: server.URL + "?x=0"},
+		{panoId: "pano", zoom: 1, x: 1, y: 0, url: server.URL + "?x=1"},
+	}
+
+	result, err := fetcher.getTilesFromConfig(tiles)
+	if err != nil {
+		t.Fatalf("expected a partial result instead of an error, got: %v", err)
+	}
+
+	if len(result.Tiles) != 1 {
+		t.Fatalf("expected 1 successful tile, got %d", len(result.Tiles))
+	}
+	if len(result.FailedTiles) != 1 {
+		t.Fatalf("expected 1 failed tile, got %d", len(result.FailedTiles))
+	}
+	if result.FailedTiles[0].x != 0 {
+		t.Fatalf("expected tile (0,0) to be the failed one, got (%d,%d)", result.FailedTiles[0].x, result.FailedTiles[0].y)
+	}
+}
+
+func TestTileFetcher_GetTiles_RespectsRateLimit(t *testing.T) {
+	const limit = 20 * time.Millisecond
+
+	var mu sync.Mutex
+	var lastReq time.Time
+	var minGap time.Duration = time.Hour
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		if !lastReq.IsZero() {
+			if gap := time.Since(lastReq); gap < minGap {
+				minGap = gap
+			}
+		}
+		lastReq = time.Now()
+		mu.Unlock()
+
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("tile-data"))
+	}))
+	defer server.Close()
+
+	rl := rate.NewLimiter(rate.Every(limit), 1)
+	rlClient := NewClient(rl)
+	fetcher := NewTileFetcher(rlClient, 8, nil)
+
+	zoom := 3
+	tiles := getTilesConfig("pano", zoom, getDimensionsFromZoom(zoom))
+	for i := range tiles {
+		tiles[i].url = server.URL
+	}
+
+	if _, err := fetcher.getTilesFromConfig(tiles); err != nil {
+		t.Fatalf("getTiles returned error: %v", err)
+	}
+
+	if minGap < limit-5*time.Millisecond {
+		t.Fatalf("requests fired faster than the rate limit: gap=%v limit=%v", minGap, limit)
+	}
+}
+
+func TestTileFetcher_GetTiles_OrderIndependentComposition(t *testing.T) {
+	var counter int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&counter, 1)
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(fmt.Sprintf("tile-%d", n)))
+	}))
+	defer server.Close()
+
+	rl := rate.NewLimiter(rate.Inf, 1)
+	rlClient := NewClient(rl)
+	fetcher := NewTileFetcher(rlClient, 8, nil)
+
+	zoom := 3
+	tiles := getTilesConfig("pano", zoom, getDimensionsFromZoom(zoom))
+	for i := range tiles {
+		tiles[i].url = server.URL
+	}
+
+	result, err := fetcher.getTilesFromConfig(tiles)
+	if err != nil {
+		t.Fatalf("getTiles returned error: %v", err)
+	}
+
+	if len(result.Tiles) != len(tiles) {
+		t.Fatalf("expected %d tiles, got %d", len(tiles), len(result.Tiles))
+	}
+
+	seen := make(map[[2]int]bool, len(result.Tiles))
+	for _, r := range result.Tiles {
+		seen[[2]int{r.x, r.y}] = true
+	}
+
+	for _, tile := range tiles {
+		if !seen[[2]int{tile.x, tile.y}] {
+			t.Fatalf("missing tile %d,%d in results regardless of arrival order", tile.x, tile.y)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/http"
+)
+
+// PanoMeta is a pano's native resolution, as reported by Google rather than
+// assumed: panos don't all max out at the same zoom level, and fetching
+// tiles past a pano's real grid silently returns blank JPEGs.
+type PanoMeta struct {
+	ImageWidth    int
+	ImageHeight   int
+	TileWidth     int
+	TileHeight    int
+	NumZoomLevels int
+}
+
+type panoMetaXML struct {
+	DataProperties struct {
+		ImageWidth    int `xml:"image_width,attr"`
+		ImageHeight   int `xml:"image_height,attr"`
+		TileWidth     int `xml:"tile_width,attr"`
+		TileHeight    int `xml:"tile_height,attr"`
+		NumZoomLevels int `xml:"num_zoom_levels,attr"`
+	} `xml:"data_properties"`
+}
+
+func makePanoMetaUrl(panoId string) string {
+	return fmt.Sprintf("https://cbk0.google.com/cbk?output=xml&panoid=%s", panoId)
+}
+
+// fetchPanoMetadata looks up a pano's real tile grid so callers don't have
+// to guess a zoom level that may not exist for this particular pano.
+func fetchPanoMetadata(panoId string, rlClient *RLHTTPClient) (*PanoMeta, error) {
+	req, err := http.NewRequest("GET", makePanoMetaUrl(panoId), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := rlClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("pano metadata for %s: server returned %s", panoId, resp.Status)
+	}
+
+	var doc panoMetaXML
+	if err := xml.NewDecoder(resp.Body).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("pano metadata for %s: %w", panoId, err)
+	}
+
+	meta := &PanoMeta{
+		ImageWidth:    doc.DataProperties.ImageWidth,
+		ImageHeight:   doc.DataProperties.ImageHeight,
+		TileWidth:     doc.DataProperties.TileWidth,
+		TileHeight:    doc.DataProperties.TileHeight,
+		NumZoomLevels: doc.DataProperties.NumZoomLevels,
+	}
+
+	if err := meta.validate(); err != nil {
+		return nil, fmt.Errorf("pano metadata for %s: %w", panoId, err)
+	}
+
+	return meta, nil
+}
+
+// validate rejects a PanoMeta with fields too broken to compute a tile grid
+// from, e.g. an unexpected XML shape or a private/unavailable panoId whose
+// response doesn't carry real data_properties attributes.
+func (m *PanoMeta) validate() error {
+	if m.NumZoomLevels <= 0 {
+		return fmt.Errorf("invalid num_zoom_levels %d", m.NumZoomLevels)
+	}
+	if m.TileWidth <= 0 || m.TileHeight <= 0 {
+		return fmt.Errorf("invalid tile size %dx%d", m.TileWidth, m.TileHeight)
+	}
+	if m.ImageWidth <= 0 || m.ImageHeight <= 0 {
+		return fmt.Errorf("invalid image size %dx%d", m.ImageWidth, m.ImageHeight)
+	}
+	return nil
+}
+
+// MaxZoom is the highest zoom level this pano actually has tiles for.
+func (m *PanoMeta) MaxZoom() int {
+	return m.NumZoomLevels - 1
+}
+
+// BestZoom picks the highest zoom level that doesn't exceed maxZoom. A
+// non-positive maxZoom means "no cap".
+func (m *PanoMeta) BestZoom(maxZoom int) int {
+	zoom := m.MaxZoom()
+	if maxZoom > 0 && zoom > maxZoom {
+		zoom = maxZoom
+	}
+	if zoom < 0 {
+		zoom = 0
+	}
+	return zoom
+}
+
+// DimensionsAtZoom computes the real tile grid for zoom, derived from the
+// pano's native resolution rather than assumed from a fixed 2:1 ratio.
+func (m *PanoMeta) DimensionsAtZoom(zoom int) dimensions {
+	shift := m.MaxZoom() - zoom
+	if shift < 0 {
+		shift = 0
+	}
+	scale := 1 << uint(shift)
+
+	return dimensions{
+		width:  ceilDiv(m.ImageWidth/scale, m.TileWidth),
+		height: ceilDiv(m.ImageHeight/scale, m.TileHeight),
+	}
+}
+
+func ceilDiv(a, b int) int {
+	if b == 0 {
+		return 0
+	}
+	return (a + b - 1) / b
+}
@@ -0,0 +1,71 @@
+package main
+
+import "testing"
+
+func TestPanoMeta_BestZoom(t *testing.T) {
+	meta := &PanoMeta{NumZoomLevels: 5} // max zoom 4
+
+	if got := meta.BestZoom(0); got != 4 {
+		t.Fatalf("got %d, want 4 (no cap)", got)
+	}
+	if got := meta.BestZoom(2); got != 2 {
+		t.Fatalf("got %d, want 2 (capped)", got)
+	}
+	if got := meta.BestZoom(10); got != 4 {
+		t.Fatalf("got %d, want 4 (cap above native max)", got)
+	}
+}
+
+func TestPanoMeta_DimensionsAtZoom(t *testing.T) {
+	meta := &PanoMeta{
+		ImageWidth:    16384,
+		ImageHeight:   8192,
+		TileWidth:     512,
+		TileHeight:    512,
+		NumZoomLevels: 6, // max zoom 5
+	}
+
+	dim := meta.DimensionsAtZoom(meta.MaxZoom())
+	if dim.width != 32 || dim.height != 16 {
+		t.Fatalf("got %+v, want {32 16} at native zoom", dim)
+	}
+
+	dim = meta.DimensionsAtZoom(meta.MaxZoom() - 1)
+	if dim.width != 16 || dim.height != 8 {
+		t.Fatalf("got %+v, want {16 8} one zoom level down", dim)
+	}
+}
+
+func TestPanoMeta_DimensionsAtZoom_NoZoomLevelsDoesNotPanic(t *testing.T) {
+	meta := &PanoMeta{} // NumZoomLevels defaults to 0, as a malformed response would decode to
+
+	dim := meta.DimensionsAtZoom(meta.BestZoom(0))
+	if dim.width != 0 || dim.height != 0 {
+		t.Fatalf("got %+v, want {0 0} since TileWidth/TileHeight are also 0", dim)
+	}
+}
+
+func TestPanoMeta_Validate(t *testing.T) {
+	valid := PanoMeta{ImageWidth: 16384, ImageHeight: 8192, TileWidth: 512, TileHeight: 512, NumZoomLevels: 6}
+	if err := valid.validate(); err != nil {
+		t.Fatalf("expected a well-formed PanoMeta to validate, got: %v", err)
+	}
+
+	cases := []struct {
+		name string
+		meta PanoMeta
+	}{
+		{"zero num_zoom_levels", PanoMeta{ImageWidth: 1, ImageHeight: 1, TileWidth: 1, TileHeight: 1, NumZoomLevels: 0}},
+		{"negative num_zoom_levels", PanoMeta{ImageWidth: 1, ImageHeight: 1, TileWidth: 1, TileHeight: 1, NumZoomLevels: -1}},
+		{"zero tile size", PanoMeta{ImageWidth: 1, ImageHeight: 1, TileWidth: 0, TileHeight: 0, NumZoomLevels: 1}},
+		{"zero image size", PanoMeta{ImageWidth: 0, ImageHeight: 0, TileWidth: 1, TileHeight: 1, NumZoomLevels: 1}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if err := c.meta.validate(); err == nil {
+				t.Fatal("expected an error for malformed pano metadata")
+			}
+		})
+	}
+}
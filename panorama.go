@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/davidbyttow/govips/v2/vips"
+)
+
+// Panorama turns a panoId into a composited equirectangular image, reusing
+// the same tiles -> composite pipeline main used to run as a one-shot script.
+type Panorama struct {
+	Fetcher *TileFetcher
+}
+
+func NewPanorama(fetcher *TileFetcher) *Panorama {
+	return &Panorama{Fetcher: fetcher}
+}
+
+// Fetch composites the pano identified by panoId at the highest zoom level
+// its metadata reports, capped at maxZoom (a non-positive maxZoom means "no
+// cap"). If the metadata lookup fails, it falls back to defaultZoom (still
+// capped at maxZoom) and the fixed 2:1 tile grid assumption.
+func (p *Panorama) Fetch(panoId string, maxZoom int) (*vips.ImageRef, error) {
+	zoom, dim := p.resolveZoom(panoId, maxZoom)
+
+	result, err := p.Fetcher.getTiles(panoId, zoom, dim)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(result.FailedTiles) > 0 {
+		log.Printf("[Panorama] %s: %d/%d tiles failed, returning a partial pano", panoId, len(result.FailedTiles), len(result.FailedTiles)+len(result.Tiles))
+	}
+
+	return compositePano(result.Tiles, dim)
+}
+
+func (p *Panorama) resolveZoom(panoId string, maxZoom int) (int, dimensions) {
+	meta, err := fetchPanoMetadata(panoId, p.Fetcher.RLClient)
+	if err != nil {
+		zoom := defaultZoom
+		if maxZoom > 0 && maxZoom < zoom {
+			zoom = maxZoom
+		}
+
+		log.Printf("[Panorama] %s: metadata lookup failed, falling back to zoom %d: %v", panoId, zoom, err)
+		return zoom, getDimensionsFromZoom(zoom)
+	}
+
+	zoom := meta.BestZoom(maxZoom)
+	return zoom, meta.DimensionsAtZoom(zoom)
+}
+
+// contentTypeForFormat maps a requested format query value to the
+// Content-Type exportImage will serve it with, erroring on anything we
+// don't know how to export.
+func contentTypeForFormat(format string) (string, error) {
+	switch format {
+	case "", "jpeg", "jpg":
+		return "image/jpeg", nil
+	case "png":
+		return "image/png", nil
+	case "webp":
+		return "image/webp", nil
+	default:
+		return "", fmt.Errorf("unsupported format %q", format)
+	}
+}
+
+// exportImage encodes image in the requested format, returning the encoded
+// bytes alongside the Content-Type a caller should serve them with.
+func exportImage(image *vips.ImageRef, format string) ([]byte, string, error) {
+	contentType, err := contentTypeForFormat(format)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var data []byte
+	switch format {
+	case "", "jpeg", "jpg":
+		data, _, err = image.Export(vips.NewDefaultJPEGExportParams())
+	case "png":
+		data, _, err = image.Export(vips.NewDefaultPNGExportParams())
+	case "webp":
+		data, _, err = image.Export(vips.NewWebpExportParams())
+	}
+
+	return data, contentType, err
+}
@@ -0,0 +1,35 @@
+package main
+
+import "testing"
+
+func TestContentTypeForFormat(t *testing.T) {
+	cases := map[string]string{
+		"":     "image/jpeg",
+		"jpeg": "image/jpeg",
+		"jpg":  "image/jpeg",
+		"png":  "image/png",
+		"webp": "image/webp",
+	}
+
+	for format, want := range cases {
+		got, err := contentTypeForFormat(format)
+		if err != nil {
+			t.Fatalf("contentTypeForFormat(%q) returned error: %v", format, err)
+		}
+		if got != want {
+			t.Fatalf("contentTypeForFormat(%q) = %q, want %q", format, got, want)
+		}
+	}
+}
+
+func TestContentTypeForFormat_Unsupported(t *testing.T) {
+	if _, err := contentTypeForFormat("bmp"); err == nil {
+		t.Fatal("expected an error for an unsupported format")
+	}
+}
+
+func TestExportImage_UnsupportedFormat(t *testing.T) {
+	if _, _, err := exportImage(nil, "bmp"); err == nil {
+		t.Fatal("expected an error for an unsupported format before touching the image")
+	}
+}
@@ -0,0 +1,265 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+
+	"github.com/davidbyttow/govips/v2/vips"
+)
+
+const defaultFaceSize = 1024
+const defaultPerspectiveSize = 1024
+
+// CubemapFaces holds the six faces of a cubemap projected from an
+// equirectangular panorama, named after the world axis each one faces.
+type CubemapFaces struct {
+	PosX, NegX, PosY, NegY, PosZ, NegZ *vips.ImageRef
+}
+
+func (f *CubemapFaces) all() [6]*vips.ImageRef {
+	return [6]*vips.ImageRef{f.PosX, f.NegX, f.PosY, f.NegY, f.PosZ, f.NegZ}
+}
+
+// direction is a unit vector in the panorama's world space: +X right, +Y up,
+// +Z forward (the direction lon=0, lat=0 points at).
+type direction struct {
+	x, y, z float64
+}
+
+func (d direction) normalize() direction {
+	n := math.Sqrt(d.x*d.x + d.y*d.y + d.z*d.z)
+	return direction{d.x / n, d.y / n, d.z / n}
+}
+
+// equirectUV converts a world-space direction into normalized (u, v)
+// texture coordinates on the source equirectangular image, u,v in [0,1].
+func equirectUV(d direction) (u, v float32) {
+	lon := math.Atan2(d.x, d.z)
+	lat := math.Asin(clamp(d.y, -1, 1))
+
+	u = float32((lon + math.Pi) / (2 * math.Pi))
+	v = float32((math.Pi/2 - lat) / math.Pi)
+	return u, v
+}
+
+func clamp(v, lo, hi float64) float64 {
+	if v < lo {
+		return lo
+	}
+	if v > hi {
+		return hi
+	}
+	return v
+}
+
+func clampInt(v, lo, hi int) int {
+	if v < lo {
+		return lo
+	}
+	if v > hi {
+		return hi
+	}
+	return v
+}
+
+// bilinearSample reads img at the fractional coordinate (sx, sy), clamping
+// to the edge, and blends the four surrounding pixels.
+func bilinearSample(img image.Image, sx, sy float32, w, h int) color.RGBA {
+	x0 := int(math.Floor(float64(sx)))
+	y0 := int(math.Floor(float64(sy)))
+	fx := float64(sx) - float64(x0)
+	fy := float64(sy) - float64(y0)
+
+	at := func(x, y int) (r, g, b, a float64) {
+		cr, cg, cb, ca := img.At(clampInt(x, 0, w-1), clampInt(y, 0, h-1)).RGBA()
+		return float64(cr >> 8), float64(cg >> 8), float64(cb >> 8), float64(ca >> 8)
+	}
+
+	lerp := func(a, b, t float64) float64 { return a + (b-a)*t }
+
+	r00, g00, b00, a00 := at(x0, y0)
+	r10, g10, b10, a10 := at(x0+1, y0)
+	r01, g01, b01, a01 := at(x0, y0+1)
+	r11, g11, b11, a11 := at(x0+1, y0+1)
+
+	return color.RGBA{
+		R: uint8(lerp(lerp(r00, r10, fx), lerp(r01, r11, fx), fy)),
+		G: uint8(lerp(lerp(g00, g10, fx), lerp(g01, g11, fx), fy)),
+		B: uint8(lerp(lerp(b00, b10, fx), lerp(b01, b11, fx), fy)),
+		A: uint8(lerp(lerp(a00, a10, fx), lerp(a01, a11, fx), fy)),
+	}
+}
+
+// sampleEquirect renders a (width, height) destination image by, for each
+// pixel, computing the world-space ray via dirAt and bilinearly sampling src
+// at the corresponding equirectangular (u, v).
+//
+// govips v2 only builds an ImageRef from encoded bytes (NewImageFromBuffer),
+// not from a raw image.Image, so the per-pixel inverse mapping runs here in
+// Go and the result is handed back to libvips as a PNG-encoded buffer rather
+// than via Mapim.
+func sampleEquirect(src *vips.ImageRef, width, height int, dirAt func(px, py int) direction) (*vips.ImageRef, error) {
+	data, _, err := src.Export(vips.NewDefaultPNGExportParams())
+	if err != nil {
+		return nil, err
+	}
+
+	srcImg, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	srcW, srcH := srcImg.Bounds().Dx(), srcImg.Bounds().Dy()
+
+	out := image.NewRGBA(image.Rect(0, 0, width, height))
+	for py := 0; py < height; py++ {
+		for px := 0; px < width; px++ {
+			u, v := equirectUV(dirAt(px, py).normalize())
+			out.SetRGBA(px, py, bilinearSample(srcImg, u*float32(srcW), v*float32(srcH), srcW, srcH))
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, out); err != nil {
+		return nil, err
+	}
+
+	return vips.NewImageFromBuffer(buf.Bytes())
+}
+
+// ProjectCubemap renders the six faces of a cubemap from an equirectangular
+// panorama, each faceSize x faceSize.
+func ProjectCubemap(equirect *vips.ImageRef, faceSize int) (*CubemapFaces, error) {
+	if faceSize <= 0 {
+		faceSize = defaultFaceSize
+	}
+
+	faceDirs := map[string]func(u, v float64) direction{
+		"+x": func(u, v float64) direction { return direction{1, -v, -u} },
+		"-x": func(u, v float64) direction { return direction{-1, -v, u} },
+		"+y": func(u, v float64) direction { return direction{u, 1, v} },
+		"-y": func(u, v float64) direction { return direction{u, -1, -v} },
+		"+z": func(u, v float64) direction { return direction{u, -v, 1} },
+		"-z": func(u, v float64) direction { return direction{-u, -v, -1} },
+	}
+
+	render := func(name string) (*vips.ImageRef, error) {
+		dirFn := faceDirs[name]
+		dirAt := func(px, py int) direction {
+			u := (2*(float64(px)+0.5)/float64(faceSize) - 1)
+			v := (2*(float64(py)+0.5)/float64(faceSize) - 1)
+			return dirFn(u, v)
+		}
+
+		face, err := sampleEquirect(equirect, faceSize, faceSize, dirAt)
+		if err != nil {
+			return nil, fmt.Errorf("cubemap face %s: %w", name, err)
+		}
+
+		return face, nil
+	}
+
+	faces := &CubemapFaces{}
+	var err error
+	if faces.PosX, err = render("+x"); err != nil {
+		return nil, err
+	}
+	if faces.NegX, err = render("-x"); err != nil {
+		return nil, err
+	}
+	if faces.PosY, err = render("+y"); err != nil {
+		return nil, err
+	}
+	if faces.NegY, err = render("-y"); err != nil {
+		return nil, err
+	}
+	if faces.PosZ, err = render("+z"); err != nil {
+		return nil, err
+	}
+	if faces.NegZ, err = render("-z"); err != nil {
+		return nil, err
+	}
+
+	return faces, nil
+}
+
+// CubemapCross lays the six faces out in an unfolded cross, the common
+// layout for previewing or re-packing a cubemap as a single image.
+func (f *CubemapFaces) CubemapCross(faceSize int) (*vips.ImageRef, error) {
+	cross, err := vips.Black(faceSize*4, faceSize*3)
+	if err != nil {
+		return nil, err
+	}
+
+	type placement struct {
+		face     *vips.ImageRef
+		col, row int
+	}
+
+	placements := []placement{
+		{f.PosY, 1, 0},
+		{f.NegX, 0, 1},
+		{f.PosZ, 1, 1},
+		{f.PosX, 2, 1},
+		{f.NegZ, 3, 1},
+		{f.NegY, 1, 2},
+	}
+
+	for _, p := range placements {
+		if err := cross.Insert(p.face, p.col*faceSize, p.row*faceSize, false, &vips.ColorRGBA{}); err != nil {
+			return nil, err
+		}
+	}
+
+	return cross, nil
+}
+
+// ProjectPerspective renders a rectilinear crop of an equirectangular
+// panorama looking in the direction (yaw, pitch) with the given field of
+// view, all in degrees. yaw 0 faces the panorama's lon=0 meridian, positive
+// yaw turns right; positive pitch looks up.
+func ProjectPerspective(equirect *vips.ImageRef, yaw, pitch, fov float64, width, height int) (*vips.ImageRef, error) {
+	if width <= 0 {
+		width = defaultPerspectiveSize
+	}
+	if height <= 0 {
+		height = width
+	}
+	if fov <= 0 || fov >= 180 {
+		fov = 90
+	}
+
+	yawRad := yaw * math.Pi / 180
+	pitchRad := pitch * math.Pi / 180
+	halfFov := fov * math.Pi / 180 / 2
+	tanHalfFov := math.Tan(halfFov)
+	aspect := float64(width) / float64(height)
+
+	dirAt := func(px, py int) direction {
+		ndcX := (2*(float64(px)+0.5)/float64(width) - 1) * aspect * tanHalfFov
+		ndcY := (1 - 2*(float64(py)+0.5)/float64(height)) * tanHalfFov
+
+		d := direction{ndcX, ndcY, 1}.normalize()
+
+		// Pitch: rotate around the camera's local X axis.
+		cosP, sinP := math.Cos(pitchRad), math.Sin(pitchRad)
+		d = direction{d.x, d.y*cosP + d.z*sinP, -d.y*sinP + d.z*cosP}
+
+		// Yaw: rotate around the world Y axis.
+		cosY, sinY := math.Cos(yawRad), math.Sin(yawRad)
+		d = direction{d.x*cosY + d.z*sinY, d.y, -d.x*sinY + d.z*cosY}
+
+		return d
+	}
+
+	persp, err := sampleEquirect(equirect, width, height, dirAt)
+	if err != nil {
+		return nil, fmt.Errorf("perspective projection: %w", err)
+	}
+
+	return persp, nil
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"testing"
+)
+
+func almostEqual(a, b float32) bool {
+	return math.Abs(float64(a-b)) < 1e-3
+}
+
+func TestEquirectUV_ForwardDirectionIsImageCenter(t *testing.T) {
+	u, v := equirectUV(direction{0, 0, 1}.normalize())
+
+	if !almostEqual(u, 0.5) || !almostEqual(v, 0.5) {
+		t.Fatalf("got (%f, %f), want (0.5, 0.5) for the forward direction", u, v)
+	}
+}
+
+func TestEquirectUV_UpDirectionIsTopRow(t *testing.T) {
+	_, v := equirectUV(direction{0, 1, 0}.normalize())
+
+	if !almostEqual(v, 0) {
+		t.Fatalf("got v=%f, want 0 (top row) for straight up", v)
+	}
+}
+
+func TestEquirectUV_RightDirectionWrapsQuarterWay(t *testing.T) {
+	u, _ := equirectUV(direction{1, 0, 0}.normalize())
+
+	if !almostEqual(u, 0.75) {
+		t.Fatalf("got u=%f, want 0.75 for +X", u)
+	}
+}
+
+func TestBilinearSample_ExactPixelMatchesSource(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 2, 2))
+	img.SetRGBA(0, 0, color.RGBA{R: 10, G: 20, B: 30, A: 255})
+	img.SetRGBA(1, 0, color.RGBA{R: 200, G: 200, B: 200, A: 255})
+
+	got := bilinearSample(img, 0, 0, 2, 2)
+	want := color.RGBA{R: 10, G: 20, B: 30, A: 255}
+	if got != want {
+		t.Fatalf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestBilinearSample_MidpointBlendsNeighbors(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 2, 1))
+	img.SetRGBA(0, 0, color.RGBA{R: 0, G: 0, B: 0, A: 255})
+	img.SetRGBA(1, 0, color.RGBA{R: 200, G: 0, B: 0, A: 255})
+
+	got := bilinearSample(img, 0.5, 0, 2, 1)
+	if got.R < 90 || got.R > 110 {
+		t.Fatalf("got R=%d, want a blend close to 100", got.R)
+	}
+}
+
+func TestBilinearSample_ClampsOutOfBoundsCoordinates(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 2, 2))
+	img.SetRGBA(1, 1, color.RGBA{R: 42, G: 42, B: 42, A: 255})
+
+	got := bilinearSample(img, 5, 5, 2, 2)
+	want := color.RGBA{R: 42, G: 42, B: 42, A: 255}
+	if got != want {
+		t.Fatalf("got %+v, want the clamped edge pixel %+v", got, want)
+	}
+}
+
+func TestClamp(t *testing.T) {
+	cases := []struct {
+		v, lo, hi, want float64
+	}{
+		{0.5, -1, 1, 0.5},
+		{-2, -1, 1, -1},
+		{2, -1, 1, 1},
+	}
+
+	for _, c := range cases {
+		if got := clamp(c.v, c.lo, c.hi); got != c.want {
+			t.Fatalf("clamp(%v, %v, %v) = %v, want %v", c.v, c.lo, c.hi, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/davidbyttow/govips/v2/vips"
+)
+
+// panoramaFetcher is the subset of *Panorama the Server depends on, broken
+// out so tests can exercise request handling with a stub instead of a real
+// pipeline.
+type panoramaFetcher interface {
+	Fetch(panoId string, maxZoom int) (*vips.ImageRef, error)
+}
+
+// Server exposes a Panorama over HTTP so callers can request a pano by id
+// without shelling out to the one-shot pipeline.
+type Server struct {
+	pano panoramaFetcher
+}
+
+func NewServer(pano panoramaFetcher) *Server {
+	return &Server{pano: pano}
+}
+
+func (s *Server) routes() *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /pano/{panoId}", s.handleGetPano)
+	return mux
+}
+
+func (s *Server) ListenAndServe(addr string) error {
+	log.Printf("listening on %s", addr)
+	return http.ListenAndServe(addr, s.routes())
+}
+
+// handleGetPano fetches and composites a pano on demand, streaming the
+// result straight to the response instead of writing it to disk. zoom is a
+// cap: the pano is rendered at its highest native zoom level at or below it.
+// projection picks the output layout: "equirect" (default), "cube" (a
+// cubemap face_size cross), or "persp" (a yaw/pitch/fov rectilinear crop).
+//
+//	GET /pano/:panoId?zoom=4&format=webp&width=2048
+//	GET /pano/:panoId?projection=cube&face_size=1024
+//	GET /pano/:panoId?projection=persp&yaw=90&pitch=0&fov=90
+func (s *Server) handleGetPano(w http.ResponseWriter, r *http.Request) {
+	panoId := r.PathValue("panoId")
+	query := r.URL.Query()
+
+	maxZoom := 0
+	if raw := query.Get("zoom"); raw != "" {
+		z, err := strconv.Atoi(raw)
+		if err != nil {
+			http.Error(w, "invalid zoom", http.StatusBadRequest)
+			return
+		}
+		maxZoom = z
+	}
+
+	width := 0
+	if raw := query.Get("width"); raw != "" {
+		wd, err := strconv.Atoi(raw)
+		if err != nil {
+			http.Error(w, "invalid width", http.StatusBadRequest)
+			return
+		}
+		width = wd
+	}
+
+	format := query.Get("format")
+
+	image, err := s.pano.Fetch(panoId, maxZoom)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	switch query.Get("projection") {
+	case "", "equirect":
+		if width > 0 {
+			scale := float64(width) / float64(image.Width())
+			if err := image.Resize(scale, vips.KernelLanczos3); err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
+	case "cube":
+		faceSize := defaultFaceSize
+		if raw := query.Get("face_size"); raw != "" {
+			fs, err := strconv.Atoi(raw)
+			if err != nil {
+				http.Error(w, "invalid face_size", http.StatusBadRequest)
+				return
+			}
+			faceSize = fs
+		}
+
+		faces, err := ProjectCubemap(image, faceSize)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		image, err = faces.CubemapCross(faceSize)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	case "persp":
+		yaw := parseQueryFloat(query, "yaw", 0)
+		pitch := parseQueryFloat(query, "pitch", 0)
+		fov := parseQueryFloat(query, "fov", 90)
+
+		size := width
+		if size <= 0 {
+			size = defaultPerspectiveSize
+		}
+
+		image, err = ProjectPerspective(image, yaw, pitch, fov, size, size)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	default:
+		http.Error(w, "invalid projection", http.StatusBadRequest)
+		return
+	}
+
+	data, contentType, err := exportImage(image, format)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Write(data)
+}
+
+func parseQueryFloat(query url.Values, key string, fallback float64) float64 {
+	raw := query.Get(key)
+	if raw == "" {
+		return fallback
+	}
+
+	v, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		return fallback
+	}
+
+	return v
+}
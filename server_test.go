@@ -0,0 +1,69 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/davidbyttow/govips/v2/vips"
+)
+
+type stubFetcher struct {
+	err error
+}
+
+func (f *stubFetcher) Fetch(panoId string, maxZoom int) (*vips.ImageRef, error) {
+	return nil, f.err
+}
+
+func TestHandleGetPano_InvalidQueryParams(t *testing.T) {
+	cases := []struct {
+		name       string
+		query      string
+		wantStatus int
+	}{
+		{"invalid zoom", "?zoom=abc", http.StatusBadRequest},
+		{"invalid width", "?width=abc", http.StatusBadRequest},
+		{"invalid face_size", "?projection=cube&face_size=abc", http.StatusBadRequest},
+		{"invalid projection", "?projection=bogus", http.StatusBadRequest},
+		{"fetch error", "", http.StatusBadGateway},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			fetchErr := error(nil)
+			if c.name == "fetch error" {
+				fetchErr = errors.New("upstream boom")
+			}
+
+			server := NewServer(&stubFetcher{err: fetchErr})
+			ts := httptest.NewServer(server.routes())
+			defer ts.Close()
+
+			resp, err := http.Get(ts.URL + "/pano/testpano" + c.query)
+			if err != nil {
+				t.Fatalf("GET failed: %v", err)
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != c.wantStatus {
+				t.Fatalf("got status %d, want %d", resp.StatusCode, c.wantStatus)
+			}
+		})
+	}
+}
+
+func TestParseQueryFloat(t *testing.T) {
+	query := httptest.NewRequest(http.MethodGet, "/?yaw=45.5&bad=notanumber", nil).URL.Query()
+
+	if got := parseQueryFloat(query, "yaw", 0); got != 45.5 {
+		t.Fatalf("got %v, want 45.5", got)
+	}
+	if got := parseQueryFloat(query, "missing", 90); got != 90 {
+		t.Fatalf("got %v, want the fallback 90", got)
+	}
+	if got := parseQueryFloat(query, "bad", 7); got != 7 {
+		t.Fatalf("got %v, want the fallback 7 for an unparsable value", got)
+	}
+}